@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"www.genji.xin/backend/ffind/internal/index"
+)
+
+var (
+	indexExts        []string
+	indexExcludeDirs []string
+	indexMaxFileSize int64
+	indexEncoding    string
+	indexNoIgnore    bool
+	indexHidden      bool
+	indexIgnoreFile  string
+	forceReindex     bool
+)
+
+var indexCmd = &cobra.Command{
+	Use:   "index [path]",
+	Short: "为目录建立/刷新 trigram 倒排索引，供 isearch 使用",
+	Long: `ffind index 会扫描目录下的所有文件，建立一份 trigram 倒排索引并
+缓存到 ~/.cache/ffind/<repo-hash>.idx。再次运行时默认只做增量刷新
+（对比 mtime/size，只重新读取变化过的文件），--reindex 可强制全量重建。`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		root := "."
+		if len(args) == 1 {
+			root = args[0]
+		}
+
+		cachePath, err := index.CachePath(root)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+
+		opts := index.BuildOptions{
+			Exts:        indexExts,
+			ExcludeDirs: indexExcludeDirs,
+			MaxFileSize: indexMaxFileSize,
+			Encoding:    indexEncoding,
+			NoIgnore:    indexNoIgnore,
+			Hidden:      indexHidden,
+			IgnoreFile:  indexIgnoreFile,
+		}
+
+		idx, err := loadOrBuildIndex(root, cachePath, opts, forceReindex)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+
+		if err := idx.Save(cachePath); err != nil {
+			fmt.Printf("保存索引失败: %v\n", err)
+			return
+		}
+		fmt.Printf("已为 %s 建立索引：%d 个文件，缓存于 %s\n", root, countLive(idx), cachePath)
+	},
+}
+
+// loadOrBuildIndex 优先复用缓存并做增量刷新，缓存不存在、损坏或 root 不
+// 匹配、以及显式 --reindex 时才会走全量 Build。
+func loadOrBuildIndex(root, cachePath string, opts index.BuildOptions, reindex bool) (*index.Index, error) {
+	if !reindex {
+		if idx, err := index.Load(cachePath); err == nil && idx.Root == root {
+			if err := idx.Refresh(opts); err != nil {
+				return nil, err
+			}
+			return idx, nil
+		}
+	}
+	return index.Build(root, opts)
+}
+
+func countLive(idx *index.Index) int {
+	n := 0
+	for _, f := range idx.Files {
+		if f.Path != "" {
+			n++
+		}
+	}
+	return n
+}
+
+func init() {
+	indexCmd.Flags().StringSliceVarP(&indexExts, "ext", "e", []string{}, "只索引指定扩展名（如 go,md,txt）")
+	indexCmd.Flags().StringSliceVar(&indexExcludeDirs, "exclude-dir", []string{".git", "node_modules", "vendor"}, "排除目录")
+	indexCmd.Flags().Int64Var(&indexMaxFileSize, "max-file-size", 10*1024*1024, "跳过超过该大小（字节）的文件")
+	indexCmd.Flags().StringVar(&indexEncoding, "encoding", "auto", "源文件编码：auto|utf8|utf16|gbk|shiftjis|eucjp")
+	indexCmd.Flags().BoolVar(&indexNoIgnore, "no-ignore", false, "不解析 .gitignore/.hgignore/.ptignore 等忽略文件")
+	indexCmd.Flags().BoolVar(&indexHidden, "hidden", false, "索引时包含以 . 开头的隐藏文件/目录")
+	indexCmd.Flags().StringVar(&indexIgnoreFile, "ignore-file", "", "额外加载一份全局忽略规则文件")
+	indexCmd.Flags().BoolVar(&forceReindex, "reindex", false, "忽略已有缓存，强制全量重建索引")
+	rootCmd.AddCommand(indexCmd)
+}
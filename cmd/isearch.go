@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"www.genji.xin/backend/ffind/internal/index"
+	"www.genji.xin/backend/ffind/internal/searcher"
+)
+
+var (
+	isearchIgnoreCase  bool
+	isearchExts        []string
+	isearchExcludeDirs []string
+	isearchWorkers     int
+	isearchRegexp      bool
+	isearchBefore      int
+	isearchAfter       int
+	isearchContext     int
+	isearchMaxFileSize int64
+	isearchFormat      string
+	isearchEncoding    string
+	isearchNoIgnore    bool
+	isearchHidden      bool
+	isearchIgnoreFile  string
+	isearchReindex     bool
+)
+
+var isearchCmd = &cobra.Command{
+	Use:   "isearch <keyword> [path]",
+	Short: "基于 trigram 索引的增量搜索，大仓库下比全量扫描快得多",
+	Long: `ffind isearch 先把 keyword/正则分解成一组必须出现的 trigram，
+用 ffind index 建立的倒排索引交出候选文件集合，再只在候选文件上跑一次
+真正的正则匹配。索引不存在时会自动建立，已存在时自动做增量刷新。`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		keyword := args[0]
+		root := "."
+		if len(args) == 2 {
+			root = args[1]
+		}
+
+		cachePath, err := index.CachePath(root)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+
+		opts := index.BuildOptions{
+			Exts:        isearchExts,
+			ExcludeDirs: isearchExcludeDirs,
+			MaxFileSize: isearchMaxFileSize,
+			Encoding:    isearchEncoding,
+			NoIgnore:    isearchNoIgnore,
+			Hidden:      isearchHidden,
+			IgnoreFile:  isearchIgnoreFile,
+		}
+
+		idx, err := loadOrBuildIndex(root, cachePath, opts, isearchReindex)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+		if err := idx.Save(cachePath); err != nil {
+			// 索引保存失败不影响本次搜索结果，只是下次又要重新扫描
+			fmt.Printf("保存索引失败: %v\n", err)
+		}
+
+		candidates, filtered := idx.Candidates(keyword, isearchRegexp, isearchIgnoreCase)
+		if !filtered {
+			fmt.Println("关键词过短或正则无法被 trigram 索引过滤，回退为全量扫描。")
+		}
+
+		reporter, err := searcher.NewReporter(isearchFormat, os.Stdout)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+
+		cfg := searcher.Config{
+			StartDir:    root,
+			Keyword:     keyword,
+			IgnoreCase:  isearchIgnoreCase,
+			Exts:        isearchExts,
+			ExcludeDirs: isearchExcludeDirs,
+			Workers:     isearchWorkers,
+			Regexp:      isearchRegexp,
+			Before:      isearchBefore,
+			After:       isearchAfter,
+			Context:     isearchContext,
+			MaxFileSize: isearchMaxFileSize,
+			NoIgnore:    isearchNoIgnore,
+			Hidden:      isearchHidden,
+			IgnoreFile:  isearchIgnoreFile,
+			Encoding:    isearchEncoding,
+		}
+
+		var matches, files int
+		var duration time.Duration
+		if filtered {
+			matches, files, duration, err = searcher.SearchFiles(cfg, reporter, candidates)
+		} else {
+			matches, files, duration, err = searcher.Search(cfg, reporter)
+		}
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+
+		if filtered {
+			fmt.Printf("\n找到 %d 个匹配项，分布在 %d 个文件中（trigram 候选 %d 个文件）。\n", matches, files, len(candidates))
+		} else {
+			fmt.Printf("\n找到 %d 个匹配项，分布在 %d 个文件中。\n", matches, files)
+		}
+		fmt.Printf("搜索耗时: %v\n", duration)
+	},
+}
+
+func init() {
+	isearchCmd.Flags().BoolVarP(&isearchIgnoreCase, "ignore-case", "i", false, "忽略大小写")
+	isearchCmd.Flags().StringSliceVarP(&isearchExts, "ext", "e", []string{}, "只搜索指定扩展名（如 go,md,txt）")
+	isearchCmd.Flags().StringSliceVar(&isearchExcludeDirs, "exclude-dir", []string{".git", "node_modules", "vendor"}, "排除目录")
+	isearchCmd.Flags().IntVarP(&isearchWorkers, "workers", "w", 0, "并发工作者数量（0=自动）")
+	isearchCmd.Flags().BoolVarP(&isearchRegexp, "regexp", "r", false, "使用正则表达式搜索")
+	isearchCmd.Flags().IntVarP(&isearchBefore, "before", "B", 0, "额外显示匹配行之前的 N 行")
+	isearchCmd.Flags().IntVarP(&isearchAfter, "after", "A", 0, "额外显示匹配行之后的 N 行")
+	isearchCmd.Flags().IntVarP(&isearchContext, "context", "C", 0, "额外显示匹配行前后的 N 行（覆盖 -B/-A）")
+	isearchCmd.Flags().Int64Var(&isearchMaxFileSize, "max-file-size", 10*1024*1024, "跳过超过该大小（字节）的文件")
+	isearchCmd.Flags().StringVar(&isearchFormat, "format", "color", "输出格式：color|plain|json|jsonl")
+	isearchCmd.Flags().StringVar(&isearchEncoding, "encoding", "auto", "源文件编码：auto|utf8|utf16|gbk|shiftjis|eucjp")
+	isearchCmd.Flags().BoolVar(&isearchNoIgnore, "no-ignore", false, "不解析 .gitignore/.hgignore/.ptignore 等忽略文件")
+	isearchCmd.Flags().BoolVar(&isearchHidden, "hidden", false, "搜索时包含以 . 开头的隐藏文件/目录")
+	isearchCmd.Flags().StringVar(&isearchIgnoreFile, "ignore-file", "", "额外加载一份全局忽略规则文件")
+	isearchCmd.Flags().BoolVar(&isearchReindex, "reindex", false, "忽略已有缓存，强制全量重建索引")
+	rootCmd.AddCommand(isearchCmd)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"www.genji.xin/backend/ffind/internal/searcher"
+	"www.genji.xin/backend/ffind/internal/tui"
 )
 
 const (
@@ -22,8 +23,29 @@ var (
 	excludeDirs []string
 	workers     int
 	useRegexp   bool
+	before      int
+	after       int
+	context     int
+	maxFileSize int64
+	noIgnore    bool
+	hidden      bool
+	ignoreFile  string
+	format      string
+	encoding    string
+	interactive bool
 )
 
+// matchCollector 在 --interactive 模式下代替普通 Reporter，把结果收集到
+// 内存里，等所有路径搜索完毕后一次性交给 tui.Run 展示，而不是边搜边打印。
+type matchCollector struct {
+	matches []searcher.Match
+}
+
+func (c *matchCollector) ReportFile(path string, matches []searcher.Match) {
+	c.matches = append(c.matches, matches...)
+}
+func (c *matchCollector) Close() {}
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:     "ffind [keyword] [path...]",
@@ -44,6 +66,15 @@ var rootCmd = &cobra.Command{
 			searchPaths = []string{"."}
 		}
 
+		// json/jsonl 是给脚本/编辑器消费的结构化输出，不掺杂人类可读的统计文字
+		structured := format == "json" || format == "jsonl"
+
+		// --interactive 下所有路径共用同一个 collector，最后统一交给 TUI
+		var collector *matchCollector
+		if interactive {
+			collector = &matchCollector{}
+		}
+
 		var totalMatches, totalFiles int
 		var totalDuration time.Duration
 		first := true
@@ -58,12 +89,26 @@ var rootCmd = &cobra.Command{
 			}
 
 			// 如果不是第一个路径，打印空行分隔
-			if !first {
+			if !first && !structured && !interactive {
 				fmt.Println()
 			}
 			first = false
 
-			fmt.Printf("搜索路径: %s\n", path)
+			if !structured && !interactive {
+				fmt.Printf("搜索路径: %s\n", path)
+			}
+
+			var reporter searcher.Reporter
+			if interactive {
+				reporter = collector
+			} else {
+				var err error
+				reporter, err = searcher.NewReporter(format, os.Stdout)
+				if err != nil {
+					fmt.Printf("错误: %v\n", err)
+					return
+				}
+			}
 
 			cfg := searcher.Config{
 				StartDir:    path,
@@ -73,9 +118,17 @@ var rootCmd = &cobra.Command{
 				ExcludeDirs: excludeDirs,
 				Workers:     workers,
 				Regexp:      useRegexp,
+				Before:      before,
+				After:       after,
+				Context:     context,
+				MaxFileSize: maxFileSize,
+				NoIgnore:    noIgnore,
+				Hidden:      hidden,
+				IgnoreFile:  ignoreFile,
+				Encoding:    encoding,
 			}
 
-			matches, files, duration, err := searcher.Search(cfg)
+			matches, files, duration, err := searcher.Search(cfg, reporter)
 			if err != nil {
 				fmt.Printf("错误: %v\n", err)
 				continue
@@ -85,8 +138,26 @@ var rootCmd = &cobra.Command{
 			totalFiles += files
 			totalDuration += duration
 
-			// 每个路径单独打印小结
-			fmt.Printf("→ 本路径: %d 个匹配项，%d 个文件，耗时 %v\n", matches, files, duration)
+			if !structured && !interactive {
+				// 每个路径单独打印小结
+				fmt.Printf("→ 本路径: %d 个匹配项，%d 个文件，耗时 %v\n", matches, files, duration)
+			}
+		}
+
+		if interactive {
+			picked, err := tui.Run(collector.matches)
+			if err != nil {
+				fmt.Printf("交互模式出错: %v\n", err)
+				return
+			}
+			for _, path := range picked {
+				fmt.Println(path)
+			}
+			return
+		}
+
+		if structured {
+			return
 		}
 
 		// 多路径时打印总计
@@ -117,6 +188,16 @@ func init() {
 	rootCmd.Flags().StringSliceVar(&excludeDirs, "exclude-dir", []string{".git", "node_modules", "vendor"}, "排除目录")
 	rootCmd.Flags().IntVarP(&workers, "workers", "w", 0, "并发工作者数量（0=自动）")
 	rootCmd.Flags().BoolVarP(&useRegexp, "regexp", "r", false, "使用正则表达式搜索")
+	rootCmd.Flags().IntVarP(&before, "before", "B", 0, "额外显示匹配行之前的 N 行")
+	rootCmd.Flags().IntVarP(&after, "after", "A", 0, "额外显示匹配行之后的 N 行")
+	rootCmd.Flags().IntVarP(&context, "context", "C", 0, "额外显示匹配行前后的 N 行（覆盖 -B/-A）")
+	rootCmd.Flags().Int64Var(&maxFileSize, "max-file-size", 10*1024*1024, "跳过超过该大小（字节）的文件")
+	rootCmd.Flags().BoolVar(&noIgnore, "no-ignore", false, "不解析 .gitignore/.hgignore/.ptignore 等忽略文件")
+	rootCmd.Flags().BoolVar(&hidden, "hidden", false, "搜索时包含以 . 开头的隐藏文件/目录")
+	rootCmd.Flags().StringVar(&ignoreFile, "ignore-file", "", "额外加载一份全局忽略规则文件")
+	rootCmd.Flags().StringVar(&format, "format", "color", "输出格式：color|plain|json|jsonl")
+	rootCmd.Flags().StringVar(&encoding, "encoding", "auto", "源文件编码：auto|utf8|utf16|gbk|shiftjis|eucjp")
+	rootCmd.Flags().BoolVarP(&interactive, "interactive", "I", false, "交互式浏览匹配结果，可跳转到编辑器")
 	// 添加 -v 作为 --version 的缩写
 	rootCmd.Flags().BoolP("version", "v", false, "显示版本信息")
 	rootCmd.SetVersionTemplate(`ffind {{.Version}}
@@ -0,0 +1,156 @@
+// Package charset 负责嗅探并转换非 UTF-8 编码的源文件，
+// 让 GBK、Shift_JIS、EUC-JP 等中/日文常见编码也能被正确搜索。
+package charset
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Name 是支持的编码标识，对应 --encoding 的取值。
+type Name string
+
+const (
+	Auto     Name = "auto"
+	UTF8     Name = "utf8"
+	UTF16    Name = "utf16"
+	GBK      Name = "gbk"
+	ShiftJIS Name = "shiftjis"
+	EUCJP    Name = "eucjp"
+)
+
+// sniffWindow 是用于嗅探编码的前缀字节数。
+const sniffWindow = 4096
+
+// minConfidence 低于该置信度的嗅探结果被视为不可靠，调用方应跳过该文件。
+const minConfidence = 0.5
+
+// Decode 把 data 转换为 UTF-8。name 为 Auto 时先嗅探编码再转换；
+// ok 为 false 表示文件被判定为二进制，或嗅探置信度过低，调用方应跳过该文件。
+//
+// 命令行传入的关键词/正则本身已经是 Go 运行时的 UTF-8 字符串，
+// 不需要额外转码，只有磁盘上的源文件字节需要经过这一步归一化。
+func Decode(data []byte, name Name) (decoded []byte, ok bool) {
+	if name == "" {
+		name = Auto
+	}
+	if name == Auto {
+		detected, confidence, isBinary := Sniff(data)
+		if isBinary || confidence < minConfidence {
+			return nil, false
+		}
+		name = detected
+	}
+	if name == UTF8 {
+		return data, true
+	}
+
+	enc := encodingFor(name)
+	if enc == nil {
+		return data, true
+	}
+	out, _, err := transform.Bytes(enc.NewDecoder(), data)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// Sniff 在 data 的前 sniffWindow 字节内做一次轻量猜测：先检查 BOM 和
+// UTF-8 有效性，再用几种候选编码分别尝试解码，挑出成功率最高的一种，
+// 这是一个类似 chardet 的简化启发式，而非严格的统计模型。
+func Sniff(data []byte) (name Name, confidence float64, isBinary bool) {
+	window := data
+	if len(window) > sniffWindow {
+		window = window[:sniffWindow]
+	}
+
+	if len(window) >= 2 && ((window[0] == 0xFF && window[1] == 0xFE) || (window[0] == 0xFE && window[1] == 0xFF)) {
+		return UTF16, 1.0, false
+	}
+
+	if bytes.ContainsRune(window, 0) {
+		if looksLikeUTF16(window) {
+			return UTF16, 0.9, false
+		}
+		return "", 0, true
+	}
+
+	if utf8.Valid(window) {
+		return UTF8, 1.0, false
+	}
+
+	best := Name("")
+	bestScore := 0.0
+	for _, candidate := range []Name{GBK, ShiftJIS, EUCJP} {
+		if score := decodeScore(window, candidate); score > bestScore {
+			bestScore = score
+			best = candidate
+		}
+	}
+	if best == "" {
+		return UTF8, 0, false
+	}
+	return best, bestScore, false
+}
+
+// encodingFor 把编码名称映射到 golang.org/x/text 提供的 Encoding 实现。
+func encodingFor(name Name) encoding.Encoding {
+	switch name {
+	case UTF16:
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	case GBK:
+		return simplifiedchinese.GBK
+	case ShiftJIS:
+		return japanese.ShiftJIS
+	case EUCJP:
+		return japanese.EUCJP
+	default:
+		return nil
+	}
+}
+
+func looksLikeUTF16(window []byte) bool {
+	if len(window) >= 2 {
+		if (window[0] == 0xFF && window[1] == 0xFE) || (window[0] == 0xFE && window[1] == 0xFF) {
+			return true
+		}
+	}
+	// 没有 BOM 时，用"接近一半字节是 0x00"这个粗略特征兜底判断
+	zero := 0
+	for _, b := range window {
+		if b == 0 {
+			zero++
+		}
+	}
+	return len(window) > 0 && float64(zero)/float64(len(window)) > 0.3
+}
+
+// decodeScore 尝试用 candidate 编码解码 window，以能成功消费的字节比例
+// 乘以解码结果中非替换字符（U+FFFD 以外）的比例，作为该编码置信度的近似值。
+func decodeScore(window []byte, candidate Name) float64 {
+	if len(window) == 0 {
+		return 0
+	}
+	enc := encodingFor(candidate)
+	if enc == nil {
+		return 0
+	}
+	out, n, err := transform.Bytes(enc.NewDecoder(), window)
+	if err != nil && n == 0 {
+		return 0
+	}
+	score := float64(n) / float64(len(window))
+	// x/text 的解码器遇到非法字节时写出 U+FFFD 并继续，而不是返回错误
+	if runes := utf8.RuneCount(out); runes > 0 {
+		bad := bytes.Count(out, []byte(string(utf8.RuneError)))
+		score *= 1 - float64(bad)/float64(runes)
+	}
+	return score
+}
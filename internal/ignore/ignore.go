@@ -0,0 +1,216 @@
+// Package ignore 实现类似 gitignore 的忽略规则解析与匹配，
+// 供 searcher 在遍历目录时跳过不需要搜索的文件/目录。
+package ignore
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ignoreFileNames 是遍历目录时会自动识别的 ignore 文件名。
+var ignoreFileNames = []string{".gitignore", ".hgignore", ".ptignore"}
+
+// pattern 是一条 ignore 规则编译后的结果。
+type pattern struct {
+	negate   bool           // 以 "!" 开头，表示取消忽略
+	dirOnly  bool           // 以 "/" 结尾，只匹配目录
+	anchored bool           // 含有除结尾外的 "/"，只相对 ignore 文件所在目录匹配
+	re       *regexp.Regexp // 由 glob 转换而来的匹配正则
+}
+
+// Matcher 维护从根目录到当前目录、按 gitignore 语义生效的忽略规则栈。
+// 子目录中的 ignore 文件优先级高于父目录。
+type Matcher struct {
+	root     string
+	noIgnore bool
+	hidden   bool
+	global   []pattern
+
+	mu    sync.Mutex
+	cache map[string][]pattern // 按目录缓存已解析的规则，避免重复 IO
+}
+
+// NewMatcher 创建一个 Matcher。
+// noIgnore 对应 --no-ignore，关闭后只做隐藏文件过滤（由 hidden 控制）。
+// hidden 对应 --hidden，为 true 时不过滤以 "." 开头的文件/目录。
+// extraIgnoreFile 对应 --ignore-file，会在根目录额外加载一份全局规则。
+func NewMatcher(root string, noIgnore, hidden bool, extraIgnoreFile string) *Matcher {
+	m := &Matcher{
+		root:     root,
+		noIgnore: noIgnore,
+		hidden:   hidden,
+		cache:    make(map[string][]pattern),
+	}
+	if noIgnore {
+		return m
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		m.global = append(m.global, loadPatterns(filepath.Join(home, ".ffindignore"))...)
+	}
+	if extraIgnoreFile != "" {
+		m.global = append(m.global, loadPatterns(extraIgnoreFile)...)
+	}
+	return m
+}
+
+// Match 判断 path 是否应当被忽略。isDir 指明 path 本身是否是目录，
+// 用于匹配仅作用于目录的规则（以 "/" 结尾的 pattern）。
+func (m *Matcher) Match(path string, isDir bool) bool {
+	if m.isHidden(path) {
+		return true
+	}
+	if m.noIgnore {
+		return false
+	}
+
+	rel, err := filepath.Rel(m.root, path)
+	if err != nil {
+		rel = path
+	}
+	rel = filepath.ToSlash(rel)
+	segments := strings.Split(rel, "/")
+
+	ignored := false
+	if matched, negate := matchPatterns(m.global, rel, segments[len(segments)-1], isDir); matched {
+		ignored = !negate
+	}
+
+	// 从根目录向下逐级应用各级 .gitignore/.hgignore/.ptignore，
+	// 越深的目录规则越靠后生效，从而覆盖父目录的规则。
+	dir := m.root
+	for i := range segments {
+		rules := m.rulesFor(dir)
+		subPath := strings.Join(segments[i:], "/")
+		if matched, negate := matchPatterns(rules, subPath, segments[len(segments)-1], isDir); matched {
+			ignored = !negate
+		}
+		if i < len(segments)-1 {
+			dir = filepath.Join(dir, segments[i])
+		}
+	}
+
+	return ignored
+}
+
+func (m *Matcher) isHidden(path string) bool {
+	if m.hidden {
+		return false
+	}
+	base := filepath.Base(path)
+	return base != "." && base != ".." && strings.HasPrefix(base, ".")
+}
+
+// rulesFor 返回 dir 目录下的 ignore 规则，解析结果会被缓存。
+func (m *Matcher) rulesFor(dir string) []pattern {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if patterns, ok := m.cache[dir]; ok {
+		return patterns
+	}
+
+	var patterns []pattern
+	for _, name := range ignoreFileNames {
+		patterns = append(patterns, loadPatterns(filepath.Join(dir, name))...)
+	}
+	m.cache[dir] = patterns
+	return patterns
+}
+
+// matchPatterns 返回规则集合里最后一条匹配的结果（gitignore 语义：
+// 后出现的规则覆盖先出现的），以及该规则是否是 "!" 取反规则。
+func matchPatterns(patterns []pattern, relPath, base string, isDir bool) (matched bool, negate bool) {
+	for _, p := range patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		target := base
+		if p.anchored {
+			target = relPath
+		}
+		if p.re.MatchString(target) {
+			matched = true
+			negate = p.negate
+		}
+	}
+	return matched, negate
+}
+
+// loadPatterns 解析一个 ignore 文件，文件不存在时返回 nil。
+func loadPatterns(path string) []pattern {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var patterns []pattern
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if p, ok := parseLine(scanner.Text()); ok {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// parseLine 解析单行 ignore 规则，空行和 "#" 注释行返回 ok=false。
+func parseLine(line string) (pattern, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return pattern{}, false
+	}
+
+	p := pattern{}
+	if strings.HasPrefix(trimmed, "!") {
+		p.negate = true
+		trimmed = trimmed[1:]
+	}
+	if strings.HasSuffix(trimmed, "/") {
+		p.dirOnly = true
+		trimmed = strings.TrimSuffix(trimmed, "/")
+	}
+	// 含有除结尾外的 "/" 视为锚定在 ignore 文件所在目录，否则在任意层级浮动匹配
+	p.anchored = strings.Contains(trimmed, "/")
+	trimmed = strings.TrimPrefix(trimmed, "/")
+
+	p.re = globToRegexp(trimmed)
+	return p, true
+}
+
+// globToRegexp 把 gitignore 风格的 glob（支持 **、*、?）转换为锚定的正则表达式。
+func globToRegexp(glob string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	runes := []rune(glob)
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '*':
+			i++
+			if i+1 < len(runes) && runes[i+1] == '/' {
+				// "**/" 匹配零个或多个完整的目录层级
+				b.WriteString("(?:.*/)?")
+				i++
+			} else {
+				b.WriteString(".*")
+			}
+		case runes[i] == '*':
+			b.WriteString("[^/]*")
+		case runes[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(runes[i])))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		// 理论上不会发生：退化为按字面量精确匹配
+		return regexp.MustCompile("^" + regexp.QuoteMeta(glob) + "$")
+	}
+	return re
+}
@@ -0,0 +1,127 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	cases := []struct {
+		glob    string
+		matches []string
+		misses  []string
+	}{
+		{glob: "*.log", matches: []string{"a.log", "b.log"}, misses: []string{"a.log.txt", "dir/a.log"}},
+		{glob: "a?c", matches: []string{"abc", "axc"}, misses: []string{"ac", "abbc"}},
+		{glob: "**/build", matches: []string{"build", "a/build", "a/b/build"}, misses: []string{"builds"}},
+		{glob: "build/**", matches: []string{"build/a", "build/a/b"}, misses: []string{"build"}},
+	}
+
+	for _, c := range cases {
+		re := globToRegexp(c.glob)
+		for _, m := range c.matches {
+			if !re.MatchString(m) {
+				t.Errorf("globToRegexp(%q) 应当匹配 %q，实际未匹配", c.glob, m)
+			}
+		}
+		for _, m := range c.misses {
+			if re.MatchString(m) {
+				t.Errorf("globToRegexp(%q) 不应匹配 %q，实际匹配了", c.glob, m)
+			}
+		}
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	if _, ok := parseLine(""); ok {
+		t.Error("空行应当返回 ok=false")
+	}
+	if _, ok := parseLine("# comment"); ok {
+		t.Error("注释行应当返回 ok=false")
+	}
+
+	p, ok := parseLine("!vendor/")
+	if !ok {
+		t.Fatal("有效规则应当返回 ok=true")
+	}
+	if !p.negate {
+		t.Error("以 ! 开头应当被解析为 negate")
+	}
+	if !p.dirOnly {
+		t.Error("以 / 结尾应当被解析为 dirOnly")
+	}
+	if p.anchored {
+		t.Error("去掉前导 ! 和结尾 / 之后不含 / 的规则不应锚定")
+	}
+
+	p, ok = parseLine("/build/output")
+	if !ok {
+		t.Fatal("有效规则应当返回 ok=true")
+	}
+	if !p.anchored {
+		t.Error("含有非结尾 / 的规则应当锚定")
+	}
+}
+
+func TestMatcherGitignoreSemantics(t *testing.T) {
+	root := t.TempDir()
+
+	mustWrite(t, filepath.Join(root, ".gitignore"), "*.log\n!important.log\nbuild/\n")
+	mustMkdir(t, filepath.Join(root, "build"))
+	mustMkdir(t, filepath.Join(root, "sub"))
+	mustWrite(t, filepath.Join(root, "sub", ".gitignore"), "!debug.log\n")
+
+	m := NewMatcher(root, false, false, "")
+
+	cases := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+		desc    string
+	}{
+		{filepath.Join(root, "a.log"), false, true, "普通 *.log 应被忽略"},
+		{filepath.Join(root, "important.log"), false, false, "根目录下的取反规则应生效"},
+		{filepath.Join(root, "build"), true, true, "dirOnly 规则应命中目录"},
+		{filepath.Join(root, "sub", "debug.log"), false, false, "子目录规则优先于父目录规则"},
+		{filepath.Join(root, "sub", "other.log"), false, true, "子目录里未被取反的 *.log 仍应被忽略"},
+	}
+
+	for _, c := range cases {
+		if got := m.Match(c.path, c.isDir); got != c.ignored {
+			t.Errorf("%s: Match(%q) = %v，期望 %v", c.desc, c.path, got, c.ignored)
+		}
+	}
+}
+
+func TestMatcherHiddenAndNoIgnore(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, ".gitignore"), "*.log\n")
+
+	hiddenPath := filepath.Join(root, ".secret")
+	logPath := filepath.Join(root, "a.log")
+
+	if !NewMatcher(root, false, false, "").Match(hiddenPath, false) {
+		t.Error("默认应当忽略隐藏文件")
+	}
+	if NewMatcher(root, false, true, "").Match(hiddenPath, false) {
+		t.Error("--hidden 应当取消隐藏文件过滤")
+	}
+	if NewMatcher(root, true, false, "").Match(logPath, false) {
+		t.Error("--no-ignore 应当关闭 .gitignore 规则")
+	}
+}
+
+func mustWrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustMkdir(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
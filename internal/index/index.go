@@ -0,0 +1,395 @@
+// Package index 实现一个持久化的 trigram 倒排索引，用于加速大仓库下的
+// 重复搜索（`ffind index` / `ffind isearch`），原理和 Google Codesearch /
+// Russ Cox 的 csearch 一致：把每个文件切成所有连续的 3 字节片段
+// （trigram），记录"哪些文件包含这个 trigram"，查询时先把关键词/正则
+// 分解成一组必须出现的 trigram，交集出候选文件集合，再对候选文件跑一次
+// 真正的正则匹配——trigram 过滤只用来剪枝，从不单独决定一次匹配是否成立。
+package index
+
+import (
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"regexp/syntax"
+	"strings"
+
+	"www.genji.xin/backend/ffind/internal/charset"
+	"www.genji.xin/backend/ffind/internal/ignore"
+)
+
+// FileEntry 记录索引里一个文件的元信息，Path 为空表示这是一个墓碑——
+// 文件已被删除或已重新索引，其在 Postings 里残留的引用可以安全忽略。
+type FileEntry struct {
+	Path    string
+	ModTime int64
+	Size    int64
+}
+
+// Index 是持久化到磁盘的 trigram 倒排索引。
+type Index struct {
+	Root     string
+	Files    []FileEntry
+	Postings map[string][]int32 // trigram -> 包含它的文件在 Files 里的下标
+}
+
+// BuildOptions 控制哪些文件参与建索引，与 searcher.Config 的同名字段语义一致。
+type BuildOptions struct {
+	Exts        []string
+	ExcludeDirs []string
+	MaxFileSize int64
+	Encoding    string
+
+	NoIgnore   bool   // --no-ignore：不解析 .gitignore 等忽略文件
+	Hidden     bool   // --hidden：索引时包含以 "." 开头的隐藏文件/目录
+	IgnoreFile string // --ignore-file：额外加载一份全局忽略规则
+}
+
+// CachePath 返回 root 对应的索引缓存文件路径：~/.cache/ffind/<repo-hash>.idx。
+func CachePath(root string) (string, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum([]byte(abs))
+	return filepath.Join(home, ".cache", "ffind", hex.EncodeToString(sum[:])+".idx"), nil
+}
+
+// Build 对 root 做一次全量扫描，建立全新的索引。
+func Build(root string, opts BuildOptions) (*Index, error) {
+	idx := &Index{Root: root, Postings: map[string][]int32{}}
+	if err := idx.scan(opts, true); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Refresh 增量刷新索引：只有 mtime/size 发生变化的文件才会被重新读取、
+// 重新分词，磁盘上已经消失的文件会被标记为墓碑；--reindex 则应直接调用
+// Build 做一次全量重建。
+func (idx *Index) Refresh(opts BuildOptions) error {
+	return idx.scan(opts, false)
+}
+
+func (idx *Index) scan(opts BuildOptions, full bool) error {
+	if full {
+		idx.Files = nil
+		idx.Postings = map[string][]int32{}
+	}
+
+	existing := make(map[string]int, len(idx.Files))
+	if !full {
+		for i, f := range idx.Files {
+			if f.Path != "" {
+				existing[f.Path] = i
+			}
+		}
+	}
+
+	ignoreMatcher := ignore.NewMatcher(idx.Root, opts.NoIgnore, opts.Hidden, opts.IgnoreFile)
+
+	seen := make(map[string]bool, len(existing))
+	err := filepath.Walk(idx.Root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // 跳过无法访问的路径
+		}
+
+		// 根目录自身不受忽略规则约束
+		if path != idx.Root && ignoreMatcher.Match(path, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir // 目录本身被忽略，不再下钻，提升大仓库遍历速度
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			base := filepath.Base(path)
+			for _, excl := range opts.ExcludeDirs {
+				if base == excl {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		maxFileSize := opts.MaxFileSize
+		if maxFileSize <= 0 {
+			maxFileSize = 10 * 1024 * 1024 // 与 searcher 未设置 MaxFileSize 时的默认上限一致
+		}
+		if info.Size() > maxFileSize {
+			return nil
+		}
+		if len(opts.Exts) > 0 {
+			ext := strings.ToLower(filepath.Ext(path))
+			allowed := false
+			for _, e := range opts.Exts {
+				if ext == "."+strings.ToLower(e) {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				return nil
+			}
+		}
+
+		seen[path] = true
+
+		if !full {
+			if id, ok := existing[path]; ok {
+				old := idx.Files[id]
+				if old.ModTime == info.ModTime().Unix() && old.Size == info.Size() {
+					return nil // 未变化，跳过，这是增量刷新最主要的省时之处
+				}
+				idx.Files[id] = FileEntry{} // 旧内容作废；Postings 里指向它的条目成为无害的悬空引用
+			}
+		}
+
+		idx.indexFile(path, info, opts)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !full {
+		for path, id := range existing {
+			if !seen[path] {
+				idx.Files[id] = FileEntry{} // 文件已从磁盘消失，标记墓碑
+			}
+		}
+	}
+	return nil
+}
+
+// indexFile 读取单个文件，归一化编码后按字节切出所有 trigram 并登记到 Postings。
+func (idx *Index) indexFile(path string, info os.FileInfo, opts BuildOptions) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	decoded, ok := charset.Decode(data, charset.Name(opts.Encoding))
+	if !ok {
+		return // 二进制文件或编码嗅探置信度过低，不参与索引
+	}
+
+	fileID := int32(len(idx.Files))
+	idx.Files = append(idx.Files, FileEntry{Path: path, ModTime: info.ModTime().Unix(), Size: info.Size()})
+
+	for trigram := range trigramSet(decoded) {
+		idx.Postings[trigram] = append(idx.Postings[trigram], fileID)
+	}
+}
+
+// trigramSet 返回 data 里所有出现过的字节级 trigram（大小写折叠后）。
+func trigramSet(data []byte) map[string]bool {
+	lower := make([]byte, len(data))
+	for i, b := range data {
+		if 'A' <= b && b <= 'Z' {
+			b += 'a' - 'A'
+		}
+		lower[i] = b
+	}
+
+	set := map[string]bool{}
+	for i := 0; i+3 <= len(lower); i++ {
+		set[string(lower[i:i+3])] = true
+	}
+	return set
+}
+
+// Load 从磁盘加载之前 Save 下来的索引。
+func Load(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var idx Index
+	if err := gob.NewDecoder(f).Decode(&idx); err != nil {
+		return nil, err
+	}
+	return &idx, nil
+}
+
+// Save 把索引持久化到 path，目录不存在时会自动创建。
+func (idx *Index) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gob.NewEncoder(f).Encode(idx)
+}
+
+// Candidates 返回 keyword（或正则 pattern）可能匹配的文件路径集合。
+// filtered 为 false 表示无法从查询里提取出任何必须出现的 trigram
+// （关键词不足 3 字节，或正则顶层是分支等），调用方应当退回全量扫描。
+func (idx *Index) Candidates(keyword string, isRegexp, ignoreCase bool) (paths []string, filtered bool) {
+	trigrams, ok := requiredTrigrams(keyword, isRegexp, ignoreCase)
+	if !ok {
+		return nil, false
+	}
+
+	var candidateIDs []int32
+	first := true
+	for t := range trigrams {
+		ids := idx.Postings[t]
+		if first {
+			candidateIDs = append(candidateIDs, ids...)
+			first = false
+			continue
+		}
+		candidateIDs = intersect(candidateIDs, ids)
+		if len(candidateIDs) == 0 {
+			break
+		}
+	}
+
+	seen := make(map[int32]bool, len(candidateIDs))
+	for _, id := range candidateIDs {
+		if seen[id] || int(id) >= len(idx.Files) {
+			continue
+		}
+		seen[id] = true
+		if p := idx.Files[id].Path; p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths, true
+}
+
+func intersect(a, b []int32) []int32 {
+	set := make(map[int32]bool, len(b))
+	for _, id := range b {
+		set[id] = true
+	}
+	out := a[:0]
+	for _, id := range a {
+		if set[id] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// requiredTrigrams 把查询分解成一组必须出现的 trigram；len(result)==0 时
+// 表示这份查询里没有任何足够长（>=3 字节）的确定性字面量，不值得用索引过滤。
+func requiredTrigrams(pattern string, isRegexp, ignoreCase bool) (map[string]bool, bool) {
+	literals, ok := literalsFor(pattern, isRegexp, ignoreCase)
+	if !ok {
+		return nil, false
+	}
+
+	// 索引只折叠 ASCII 大小写（见 trigramSet），查询必须用同样的折叠方式；
+	// 忽略大小写时非 ASCII 字符可能以别的大小写形式出现，含这类字节的
+	// trigram 不能作为必要条件。
+	fold := ignoreCase || (isRegexp && strings.Contains(pattern, "(?"))
+	set := map[string]bool{}
+	for _, lit := range literals {
+		lower := []byte(lit)
+		for i, b := range lower {
+			if 'A' <= b && b <= 'Z' {
+				lower[i] = b + 'a' - 'A'
+			}
+		}
+		for i := 0; i+3 <= len(lower); i++ {
+			t := lower[i : i+3]
+			if fold && (t[0] >= 0x80 || t[1] >= 0x80 || t[2] >= 0x80) {
+				continue
+			}
+			set[string(t)] = true
+		}
+	}
+	if len(set) == 0 {
+		return nil, false
+	}
+	return set, true
+}
+
+// literalsFor 提取查询里"一定会原样出现"的字面量片段。
+func literalsFor(pattern string, isRegexp, ignoreCase bool) ([]string, bool) {
+	if !isRegexp {
+		return []string{pattern}, true
+	}
+
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, false
+	}
+	return extractLiterals(re.Simplify()), true
+}
+
+// extractLiterals 是一个简化版的查询规划器：只处理字面量拼接
+// （OpConcat/OpLiteral）、捕获分组（OpCapture）和"至少一次"的重复
+// （OpPlus），遇到分支（OpAlternate）、可选重复等无法保证必然出现的结构
+// 时直接放弃该子树——结果只是一个保守下界，里面的每个片段都必然整串
+// 出现在匹配文本中，但反过来不成立，所以最终仍然需要用真正的正则再跑
+// 一遍候选文件。
+func extractLiterals(re *syntax.Regexp) []string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return []string{string(re.Rune)}
+	case syntax.OpConcat:
+		var out []string
+		cur := ""
+		// plusLit 记录上一个片段是 OpPlus 时的字面量：重复次数不定，后面的
+		// 字面量只能保证紧跟在最后一次重复之后，不能接到整个 cur 上
+		plusLit := ""
+		for _, sub := range re.Sub {
+			if lit, ok := singleLiteral(sub); ok {
+				if plusLit != "" {
+					out = append(out, cur)
+					cur = plusLit
+				}
+				cur += lit
+				plusLit = ""
+				if sub.Op == syntax.OpPlus {
+					plusLit = lit
+				}
+				continue
+			}
+			plusLit = ""
+			if cur != "" {
+				out = append(out, cur)
+				cur = ""
+			}
+			out = append(out, extractLiterals(sub)...)
+		}
+		if cur != "" {
+			out = append(out, cur)
+		}
+		return out
+	case syntax.OpCapture:
+		return extractLiterals(re.Sub[0])
+	case syntax.OpPlus:
+		return extractLiterals(re.Sub[0])
+	default:
+		return nil
+	}
+}
+
+// singleLiteral 判断 re 是否整体收缩为唯一一段必然出现的字面量，供
+// OpConcat 在拼接相邻片段时使用（例如 "foo+" 中的 "o+" 应当和前面的
+// "fo" 合并成一个更长的 "foo"，而不是各自拆成独立片段）。
+func singleLiteral(re *syntax.Regexp) (string, bool) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return string(re.Rune), true
+	case syntax.OpPlus:
+		return singleLiteral(re.Sub[0])
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,105 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestRequiredTrigrams(t *testing.T) {
+	set, ok := requiredTrigrams("hello", false, false)
+	if !ok {
+		t.Fatal("长度 >=3 的普通关键词应当能提取出 trigram")
+	}
+	for _, want := range []string{"hel", "ell", "llo"} {
+		if !set[want] {
+			t.Errorf("requiredTrigrams(\"hello\") 缺少 %q", want)
+		}
+	}
+
+	if _, ok := requiredTrigrams("ab", false, false); ok {
+		t.Error("长度不足 3 的关键词不应产生 trigram")
+	}
+
+	if _, ok := requiredTrigrams("foo|bar", true, false); ok {
+		t.Error("顶层分支结构不应保证必然出现，应当放弃过滤")
+	}
+}
+
+func TestExtractLiterals(t *testing.T) {
+	cases := []struct {
+		pattern string
+		want    []string
+	}{
+		{"foobar", []string{"foobar"}},
+		{"foo.*bar", []string{"foo", "bar"}},
+		{"(foo)(bar)", []string{"foo", "bar"}},
+		{"foo+", []string{"foo"}},
+	}
+
+	for _, c := range cases {
+		literals, ok := literalsFor(c.pattern, true, false)
+		if !ok {
+			t.Fatalf("literalsFor(%q) 解析失败", c.pattern)
+		}
+		if len(literals) != len(c.want) {
+			t.Fatalf("literalsFor(%q) = %v，期望 %v", c.pattern, literals, c.want)
+		}
+		for i, lit := range literals {
+			if lit != c.want[i] {
+				t.Errorf("literalsFor(%q)[%d] = %q，期望 %q", c.pattern, i, lit, c.want[i])
+			}
+		}
+	}
+
+	if literals, ok := literalsFor("foo|bar", true, false); ok && len(literals) != 0 {
+		t.Errorf("分支结构应当无法提取出确定性字面量，得到 %v", literals)
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	got := intersect([]int32{1, 2, 3, 4}, []int32{2, 4, 6})
+	want := []int32{2, 4}
+	if len(got) != len(want) {
+		t.Fatalf("intersect 结果长度不符: got %v want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("intersect 结果不符: got %v want %v", got, want)
+		}
+	}
+}
+
+func TestBuildAndCandidates(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "a.go"), "package main\n\nfunc hello() {}\n")
+	mustWrite(t, filepath.Join(root, "b.go"), "package main\n\nfunc world() {}\n")
+	mustWrite(t, filepath.Join(root, ".gitignore"), "ignored.go\n")
+	mustWrite(t, filepath.Join(root, "ignored.go"), "package main\n\nfunc hello() {}\n")
+
+	idx, err := Build(root, BuildOptions{})
+	if err != nil {
+		t.Fatalf("Build 失败: %v", err)
+	}
+
+	paths, filtered := idx.Candidates("hello", false, false)
+	if !filtered {
+		t.Fatal("普通关键词应当能被 trigram 过滤")
+	}
+	sort.Strings(paths)
+	if len(paths) != 1 || filepath.Base(paths[0]) != "a.go" {
+		t.Errorf("Candidates(\"hello\") = %v，期望只包含 a.go（ignored.go 应被 .gitignore 排除）", paths)
+	}
+
+	if _, filtered := idx.Candidates("world", false, false); !filtered {
+		t.Error("关键词 world 也应当能被过滤")
+	}
+}
+
+func mustWrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
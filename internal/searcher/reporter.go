@@ -0,0 +1,172 @@
+package searcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/fatih/color"
+)
+
+// NewReporter 根据 --format 的取值构造对应的 Reporter。
+// 支持 color（默认，带高亮的终端输出）、plain（无 ANSI 转义的纯文本）、
+// json（一次性输出完整 JSON 数组）、jsonl（每行一个 JSON 对象）。
+func NewReporter(format string, w io.Writer) (Reporter, error) {
+	switch format {
+	case "", "color":
+		return &streamReporter{w: w, color: true}, nil
+	case "plain":
+		return &streamReporter{w: w, color: false}, nil
+	case "json":
+		return &jsonReporter{w: w}, nil
+	case "jsonl":
+		return &jsonlReporter{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("未知的输出格式: %s（支持 color|plain|json|jsonl）", format)
+	}
+}
+
+// streamReporter 是默认的终端输出实现，覆盖 color（高亮）和 plain（无颜色）
+// 两种模式。同一个文件内相邻的匹配会合并上下文，避免重复打印同一行；
+// 不相邻的匹配组之间用 "--" 分隔，风格与 grep -C 一致。
+type streamReporter struct {
+	w     io.Writer
+	color bool
+}
+
+// ReportFile 一次性拿到某个文件的全部 Match，先按行号归并——同一行上的
+// 多处匹配共享一次打印、合并它们各自的高亮区间，再把上下文行首尾相连
+// 或重叠的匹配行归并到同一个窗口里，窗口之间用 "--" 分隔。
+func (r *streamReporter) ReportFile(path string, matches []Match) {
+	if len(matches) == 0 {
+		return
+	}
+	r.printHeader(path)
+
+	lineRanges := map[int][][2]int{}
+	linePreview := map[int]string{}
+	lineBefore := map[int]int{} // 行号 -> 该行要求展示的 Before 行数
+	lineAfter := map[int]int{}  // 行号 -> 该行要求展示的 After 行数
+	contextText := map[int]string{}
+	var matchLines []int
+
+	for _, m := range matches {
+		if _, ok := linePreview[m.Line]; !ok {
+			matchLines = append(matchLines, m.Line)
+		}
+		lineRanges[m.Line] = append(lineRanges[m.Line], [2]int{m.MatchStart, m.MatchEnd})
+		linePreview[m.Line] = m.Preview
+		if len(m.Before) > lineBefore[m.Line] {
+			lineBefore[m.Line] = len(m.Before)
+		}
+		if len(m.After) > lineAfter[m.Line] {
+			lineAfter[m.Line] = len(m.After)
+		}
+		firstBefore := m.Line - len(m.Before)
+		for i, b := range m.Before {
+			contextText[firstBefore+i] = b
+		}
+		for i, a := range m.After {
+			contextText[m.Line+1+i] = a
+		}
+	}
+	sort.Ints(matchLines)
+
+	type window struct{ from, to int }
+	var windows []window
+	for _, ln := range matchLines {
+		from := ln - lineBefore[ln]
+		to := ln + lineAfter[ln]
+		if n := len(windows); n > 0 && from <= windows[n-1].to+1 {
+			if to > windows[n-1].to {
+				windows[n-1].to = to
+			}
+			continue
+		}
+		windows = append(windows, window{from, to})
+	}
+
+	for wi, win := range windows {
+		if wi > 0 {
+			r.printContext("--")
+		}
+		for ln := win.from; ln <= win.to; ln++ {
+			if ranges, ok := lineRanges[ln]; ok {
+				r.printMatch(ln, linePreview[ln], ranges)
+				continue
+			}
+			if text, ok := contextText[ln]; ok {
+				r.printContext(fmt.Sprintf("%d: %s", ln, text))
+			}
+		}
+	}
+}
+
+func (r *streamReporter) Close() {}
+
+func (r *streamReporter) printHeader(path string) {
+	if r.color {
+		color.New(color.FgCyan).Fprintf(r.w, "\n%s:\n", path)
+		return
+	}
+	fmt.Fprintf(r.w, "\n%s:\n", path)
+}
+
+func (r *streamReporter) printContext(line string) {
+	if r.color {
+		color.New(color.Faint).Fprintf(r.w, "  %s\n", line)
+		return
+	}
+	fmt.Fprintf(r.w, "  %s\n", line)
+}
+
+// printMatch 打印一行的匹配内容，ranges 是该行上按出现顺序排列的
+// [start, end) 高亮区间（同一行多次匹配时会有多个）。
+func (r *streamReporter) printMatch(line int, preview string, ranges [][2]int) {
+	if !r.color {
+		fmt.Fprintf(r.w, "  %d: %s\n", line, preview)
+		return
+	}
+	color.New(color.FgYellow).Fprintf(r.w, "  %d: ", line)
+	pos := 0
+	for _, rg := range ranges {
+		fmt.Fprint(r.w, preview[pos:rg[0]])
+		color.New(color.FgYellow, color.Bold).Fprint(r.w, preview[rg[0]:rg[1]])
+		pos = rg[1]
+	}
+	fmt.Fprintln(r.w, preview[pos:])
+}
+
+// jsonReporter 把所有匹配缓存下来，在 Close 时一次性输出为 JSON 数组。
+type jsonReporter struct {
+	w       io.Writer
+	matches []Match
+}
+
+func (r *jsonReporter) ReportFile(path string, matches []Match) {
+	r.matches = append(r.matches, matches...)
+}
+
+func (r *jsonReporter) Close() {
+	if r.matches == nil {
+		r.matches = []Match{}
+	}
+	enc := json.NewEncoder(r.w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(r.matches)
+}
+
+// jsonlReporter 每收到一个 Match 就立即输出一行 JSON，便于流式消费
+// （例如通过管道交给 jq 逐行处理）。
+type jsonlReporter struct {
+	enc *json.Encoder
+}
+
+func (r *jsonlReporter) ReportFile(path string, matches []Match) {
+	for _, m := range matches {
+		_ = r.enc.Encode(m)
+	}
+}
+
+func (r *jsonlReporter) Close() {}
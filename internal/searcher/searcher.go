@@ -1,8 +1,6 @@
 package searcher
 
 import (
-	"bufio"
-	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -10,9 +8,14 @@ import (
 	"sync"
 	"time"
 
-	"github.com/fatih/color"
+	"www.genji.xin/backend/ffind/internal/charset"
+	"www.genji.xin/backend/ffind/internal/ignore"
 )
 
+// defaultMaxFileSize 是单个文件参与搜索的默认大小上限（约 10MB），
+// 超过该大小的文件视为二进制/巨型文件，直接跳过。
+const defaultMaxFileSize = 10 * 1024 * 1024
+
 type Config struct {
 	StartDir    string
 	Keyword     string
@@ -21,27 +24,168 @@ type Config struct {
 	ExcludeDirs []string
 	Workers     int // 新增：并发工作者数量
 	Regexp      bool
+
+	Before      int   // -B：匹配行之前额外显示的行数
+	After       int   // -A：匹配行之后额外显示的行数
+	Context     int   // -C：匹配行前后均显示的行数，设置后会覆盖 Before/After
+	MaxFileSize int64 // 跳过超过该大小的文件，默认 defaultMaxFileSize
+
+	NoIgnore   bool   // --no-ignore：不解析 .gitignore 等忽略文件
+	Hidden     bool   // --hidden：搜索时包含以 "." 开头的隐藏文件/目录
+	IgnoreFile string // --ignore-file：额外加载一份全局忽略规则
+
+	// Encoding 对应 --encoding：auto|utf8|utf16|gbk|shiftjis|eucjp。
+	// 为空时等同于 auto，即每个文件各自嗅探编码。
+	Encoding string
 }
 
-func Search(cfg Config) (matchCount int, fileCount int, duration time.Duration, err error) {
-	start := time.Now()
-	// 设置默认并发数
+// Match 描述一处正则/关键词匹配，是 searcher 对外暴露的最小结果单元。
+// 一行中出现多次匹配时，会产生多个 Match，彼此共享同一个 Preview。
+// Snippet 是 Match 的别名，强调它也可以被当作一段可展示的代码片段使用
+// （例如编辑器/IDE 集成、JSON 输出）。
+type Match struct {
+	Path       string   `json:"path"`
+	Line       int      `json:"line"`
+	Column     int      `json:"column"`     // 匹配起始位置的列号（从 1 开始，按字节计）
+	MatchStart int      `json:"matchStart"` // 匹配在 Preview 中的起始字节偏移
+	MatchEnd   int      `json:"matchEnd"`   // 匹配在 Preview 中的结束字节偏移
+	Preview    string   `json:"preview"`    // 匹配所在行的完整内容
+	Before     []string `json:"before,omitempty"`
+	After      []string `json:"after,omitempty"`
+}
+
+// Snippet 是 Match 的别名，用于强调其作为可展示代码片段的一面。
+type Snippet = Match
+
+// Reporter 负责消费 searcher 产生的匹配结果并决定如何展示（彩色终端、
+// 纯文本、JSON、JSON-lines……）。ReportFile 以文件为单位整批交付——
+// 同一个文件的所有 Match 只会在一次调用里出现一次，调用方不需要自己
+// 按 Path 做分组或猜测文件边界。所有调用都由同一个 goroutine 按文件
+// 完成的顺序串行触发，因此 Reporter 的实现不需要自己加锁。
+type Reporter interface {
+	ReportFile(path string, matches []Match)
+	// Close 在所有匹配都上报完毕后调用一次，用于输出收尾
+	// （例如 JSON 数组格式在这里才一次性输出整个数组）。
+	Close()
+}
+
+// applyDefaults 给未设置的 Config 字段填上默认值。Search 和 run 各自持有一份
+// Config 的值拷贝，因此两处都要调用它——既要在 Search 构建 filepath.Walk 闭包
+// 之前把 MaxFileSize 这类闭包会读取的字段改好，也要让只调用 run（如
+// SearchFiles）的路径兜底，调用两次是幂等的。
+func applyDefaults(cfg *Config) {
 	if cfg.Workers <= 0 {
 		cfg.Workers = 4 // 默认 4 个 worker
 	}
+	if cfg.Context > 0 {
+		cfg.Before = cfg.Context
+		cfg.After = cfg.Context
+	}
+	if cfg.MaxFileSize <= 0 {
+		cfg.MaxFileSize = defaultMaxFileSize
+	}
+}
 
-	keyword := cfg.Keyword
-	if cfg.IgnoreCase {
-		keyword = strings.ToLower(keyword)
+// Search 遍历 cfg.StartDir 下的所有文件并搜索 cfg.Keyword。
+func Search(cfg Config, reporter Reporter) (matchCount int, fileCount int, duration time.Duration, err error) {
+	applyDefaults(&cfg) // 必须在下面的 filepath.Walk 闭包捕获 cfg 之前完成，否则 MaxFileSize 的默认值只会应用到 run() 内部的另一份拷贝上
+	ignoreMatcher := ignore.NewMatcher(cfg.StartDir, cfg.NoIgnore, cfg.Hidden, cfg.IgnoreFile)
+
+	var walkErr error
+	matchCount, fileCount, duration = run(cfg, reporter, func(fileChan chan<- string) {
+		// 遍历目录，收集文件路径（这个过程保持单线程，避免竞争）
+		walkErr = filepath.Walk(cfg.StartDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil // 跳过无法访问的路径
+			}
+
+			// 根目录自身不受忽略规则约束
+			if path != cfg.StartDir && ignoreMatcher.Match(path, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir // 目录本身被忽略，不再下钻，提升大仓库遍历速度
+				}
+				return nil
+			}
+
+			// 排除指定目录
+			if info.IsDir() {
+				base := filepath.Base(path)
+				for _, excl := range cfg.ExcludeDirs {
+					if base == excl {
+						return filepath.SkipDir
+					}
+				}
+				return nil
+			}
+
+			// 扩展名过滤
+			if len(cfg.Exts) > 0 {
+				ext := strings.ToLower(filepath.Ext(path))
+				allowed := false
+				for _, e := range cfg.Exts {
+					if ext == "."+strings.ToLower(e) {
+						allowed = true
+						break
+					}
+				}
+				if !allowed {
+					return nil
+				}
+			}
+
+			// 只处理普通文件，且跳过超过大小上限的文件
+			if info.Mode().IsRegular() && info.Size() <= cfg.MaxFileSize {
+				fileChan <- path
+			}
+			return nil
+		})
+	})
+
+	if walkErr != nil {
+		return 0, 0, 0, walkErr
 	}
+	return matchCount, fileCount, duration, nil
+}
+
+// SearchFiles 和 Search 的区别是直接在给定的文件列表里搜索，不做目录遍历。
+// 主要供 internal/index 的 trigram 索引过滤之后调用，只在候选文件上跑一次
+// 真正的正则匹配。
+func SearchFiles(cfg Config, reporter Reporter, files []string) (matchCount int, fileCount int, duration time.Duration, err error) {
+	matchCount, fileCount, duration = run(cfg, reporter, func(fileChan chan<- string) {
+		for _, f := range files {
+			fileChan <- f
+		}
+	})
+	return matchCount, fileCount, duration, nil
+}
+
+// run 是 Search 和 SearchFiles 共用的核心：启动 worker 池处理 produce 发来的
+// 文件路径，并用单独的 goroutine 把结果交给 reporter。
+func run(cfg Config, reporter Reporter, produce func(fileChan chan<- string)) (matchCount int, fileCount int, duration time.Duration) {
+	start := time.Now()
+	applyDefaults(&cfg) // 幂等：Search 可能已经应用过一次，SearchFiles/直接调用 run 的场景仍需要在这里兜底
 
 	// 通道：用于传递待处理的文件路径
 	fileChan := make(chan string, 100)
+	// 通道：worker 产生的匹配结果，由单个 goroutine 统一消费并交给 reporter。
+	// 每个文件的所有 Match 被 processFile 攒成一个切片后整批发送，
+	// 保证不同文件的输出不会在 reporter 里交错，也不需要打印用的 mu.Lock()。
+	resultChan := make(chan []Match, 100)
 
-	// 结果统计（线程安全）
-	var mu sync.Mutex
-	matchCount = 0
-	fileCount = 0
+	// 启动结果消费 goroutine
+	var drainWg sync.WaitGroup
+	drainWg.Add(1)
+	go func() {
+		defer drainWg.Done()
+		for matches := range resultChan {
+			if len(matches) == 0 {
+				continue
+			}
+			fileCount++
+			matchCount += len(matches)
+			reporter.ReportFile(matches[0].Path, matches)
+		}
+	}()
 
 	// 启动 worker goroutine
 	var wg sync.WaitGroup
@@ -50,178 +194,164 @@ func Search(cfg Config) (matchCount int, fileCount int, duration time.Duration,
 		go func() {
 			defer wg.Done()
 			for path := range fileChan {
-				processFile(path, cfg, &mu, &matchCount, &fileCount)
+				processFile(path, cfg, resultChan)
 			}
 		}()
 	}
 
-	// 遍历目录，收集文件路径（这个过程保持单线程，避免竞争）
-	err = filepath.Walk(cfg.StartDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // 跳过无法访问的路径
-		}
+	produce(fileChan)
 
-		// 排除指定目录
-		if info.IsDir() {
-			base := filepath.Base(path)
-			for _, excl := range cfg.ExcludeDirs {
-				if base == excl {
-					return filepath.SkipDir
-				}
-			}
-			return nil
-		}
+	close(fileChan) // 所有文件收集完毕
+	wg.Wait()       // 等待所有 worker 完成
 
-		// 扩展名过滤
-		if len(cfg.Exts) > 0 {
-			ext := strings.ToLower(filepath.Ext(path))
-			allowed := false
-			for _, e := range cfg.Exts {
-				if ext == "."+strings.ToLower(e) {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				return nil
-			}
+	close(resultChan) // 所有匹配结果发送完毕
+	drainWg.Wait()    // 等待消费 goroutine 处理完剩余结果
+	reporter.Close()
+
+	duration = time.Since(start)
+	return matchCount, fileCount, duration
+}
+
+// lineIndex 记录一个文件中每一行的起始字节偏移，用于把正则匹配的字节位置
+// 换算成行号、取出某一行的文本内容。
+type lineIndex struct {
+	data       []byte
+	lineStarts []int // 每一行在 data 中的起始偏移
+	totalLines int
+}
+
+func newLineIndex(data []byte) *lineIndex {
+	lineStarts := []int{0}
+	for i := 0; i < len(data); i++ {
+		if data[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
 		}
+	}
+	totalLines := len(lineStarts)
+	if len(data) > 0 && data[len(data)-1] == '\n' {
+		totalLines-- // 文件以换行符结尾时，最后一个起始位置不构成真正的行
+	}
+	return &lineIndex{data: data, lineStarts: lineStarts, totalLines: totalLines}
+}
 
-		// 只处理普通文件
-		if info.Mode().IsRegular() {
-			fileChan <- path
+// lineOf 返回字节偏移 offset 所在的行号（从 1 开始）。
+func (li *lineIndex) lineOf(offset int) int {
+	lo, hi := 0, len(li.lineStarts)-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if li.lineStarts[mid] <= offset {
+			lo = mid
+		} else {
+			hi = mid - 1
 		}
-		return nil
-	})
+	}
+	return lo + 1
+}
 
-	close(fileChan) // 所有文件收集完毕
-	wg.Wait()       // 等待所有 worker 完成
+// text 返回第 n 行的内容（不含末尾换行符），n 从 1 开始。
+func (li *lineIndex) text(n int) string {
+	if n < 1 || n > li.totalLines {
+		return ""
+	}
+	start := li.lineStarts[n-1]
+	end := len(li.data)
+	if n < len(li.lineStarts) {
+		end = li.lineStarts[n] - 1
+	}
+	if end < start {
+		end = start
+	}
+	return strings.TrimRight(string(li.data[start:end]), "\r")
+}
 
-	if err != nil {
-		return 0, 0, 0, err
+// before 返回第 n 行之前最多 count 行的内容，按从早到晚排列。
+func (li *lineIndex) before(n, count int) []string {
+	from := n - count
+	if from < 1 {
+		from = 1
+	}
+	var lines []string
+	for ln := from; ln < n; ln++ {
+		lines = append(lines, li.text(ln))
 	}
+	return lines
+}
 
-	duration = time.Since(start)
-	return matchCount, fileCount, duration, nil
+// after 返回第 n 行之后最多 count 行的内容。
+func (li *lineIndex) after(n, count int) []string {
+	to := n + count
+	if to > li.totalLines {
+		to = li.totalLines
+	}
+	var lines []string
+	for ln := n + 1; ln <= to; ln++ {
+		lines = append(lines, li.text(ln))
+	}
+	return lines
 }
 
-// processFile 处理单个文件
-func processFile(path string, cfg Config, mu *sync.Mutex, matchCount, fileCount *int) {
-	file, err := os.Open(path)
+// processFile 处理单个文件：一次性读入整个文件，用正则批量定位所有匹配，
+// 再按 Before/After/Context 取出上下文行，最终把这个文件的所有 Match
+// 攒成一个切片，整批推送到结果 channel，交由单独的消费者 goroutine 统一展示。
+func processFile(path string, cfg Config, resultChan chan<- []Match) {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	lineNum := 0
-	fileMatched := false
-
-	var re *regexp.Regexp
-	if cfg.Regexp {
-		pattern := cfg.Keyword
-		if cfg.IgnoreCase {
-			pattern = "(?i)" + pattern
-		}
-		var err error
-		re, err = regexp.Compile(pattern)
-		if err != nil {
-			// 正则无效，跳过文件
-			return
-		}
+	// 按 Encoding 配置把源文件归一化为 UTF-8，置信度不足或判定为二进制的文件直接跳过
+	decoded, ok := charset.Decode(data, charset.Name(cfg.Encoding))
+	if !ok {
+		return
 	}
+	data = decoded
 
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
-
-		var matches []int // 匹配的起始和结束索引（成对）
-
-		if cfg.Regexp {
-			if re == nil {
-				continue
-			}
-			matches = re.FindStringIndex(line)
-			if matches == nil {
-				continue
-			}
-			// 支持一行多个匹配
-			allMatches := re.FindAllStringIndex(line, -1)
-			if len(allMatches) == 0 {
-				continue
-			}
+	pattern := cfg.Keyword
+	if !cfg.Regexp {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if cfg.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		// 正则无效，跳过文件
+		return
+	}
 
-			mu.Lock()
-			if !fileMatched {
-				color.Cyan("\n%s:", path)
-				*fileCount++
-				fileMatched = true
-			}
-			*matchCount += len(allMatches) // 每个匹配算一个
-			mu.Unlock()
-
-			color.Yellow("  %d: ", lineNum)
-
-			// 高亮所有匹配部分
-			pos := 0
-			for _, m := range allMatches {
-				start, end := m[0], m[1]
-				// 打印未匹配部分
-				fmt.Print("  " + line[pos:start])
-				// 高亮匹配部分
-				color.New(color.FgYellow, color.Bold).Print(line[start:end])
-				pos = end
-			}
-			// 打印剩余部分
-			if pos < len(line) {
-				fmt.Println("  " + line[pos:])
-			} else {
-				fmt.Println()
-			}
+	idx := re.FindAllIndex(data, -1)
+	if len(idx) == 0 {
+		return
+	}
 
-		} else {
-			// 普通关键词模式（保持你之前的高亮逻辑，推荐用这个简化版）
-			searchLine := line
-			searchKeyword := cfg.Keyword
-			if cfg.IgnoreCase {
-				searchLine = strings.ToLower(searchLine)
-				searchKeyword = strings.ToLower(searchKeyword)
-			}
+	li := newLineIndex(data)
 
-			if !strings.Contains(searchLine, searchKeyword) {
-				continue
-			}
+	matches := make([]Match, 0, len(idx))
+	for _, m := range idx {
+		ln := li.lineOf(m[0])
+		lineStart := li.lineStarts[ln-1]
+		preview := li.text(ln)
 
-			mu.Lock()
-			if !fileMatched {
-				color.Cyan("\n%s:", path)
-				*fileCount++
-				fileMatched = true
-			}
-			*matchCount++
-			mu.Unlock()
-
-			color.Yellow("  %d: ", lineNum)
-
-			// 普通模式高亮（支持忽略大小写）
-			lowerLine := strings.ToLower(line)
-			lowerKeyword := strings.ToLower(cfg.Keyword)
-			start := 0
-			for {
-				idx := strings.Index(lowerLine[start:], lowerKeyword)
-				if idx == -1 {
-					break
-				}
-				absIdx := start + idx
-				fmt.Print("  " + line[start:absIdx])
-				color.New(color.FgYellow, color.Bold).Print(line[absIdx : absIdx+len(cfg.Keyword)])
-				start = absIdx + len(cfg.Keyword)
-			}
-			if start < len(line) {
-				fmt.Println("  " + line[start:])
-			} else {
-				fmt.Println()
-			}
+		// 跨行的匹配（如正则里的 \n、\s）只在首行高亮，偏移截断到 Preview 之内
+		matchStart, matchEnd := m[0]-lineStart, m[1]-lineStart
+		if matchStart > len(preview) {
+			matchStart = len(preview)
 		}
+		if matchEnd > len(preview) {
+			matchEnd = len(preview)
+		}
+
+		matches = append(matches, Match{
+			Path:       path,
+			Line:       ln,
+			Column:     m[0] - lineStart + 1,
+			MatchStart: matchStart,
+			MatchEnd:   matchEnd,
+			Preview:    preview,
+			Before:     li.before(ln, cfg.Before),
+			After:      li.after(ln, cfg.After),
+		})
 	}
+	resultChan <- matches
 }
+
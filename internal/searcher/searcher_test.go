@@ -0,0 +1,191 @@
+package searcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// recordingReporter 把每次 ReportFile 收到的 Match 原样攒起来，供测试直接断言。
+type recordingReporter struct {
+	byFile map[string][]Match
+	closed bool
+}
+
+func (r *recordingReporter) ReportFile(path string, matches []Match) {
+	if r.byFile == nil {
+		r.byFile = map[string][]Match{}
+	}
+	r.byFile[path] = append(r.byFile[path], matches...)
+}
+
+func (r *recordingReporter) Close() { r.closed = true }
+
+func writeTempFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("写入临时文件 %s 失败: %v", path, err)
+	}
+	return path
+}
+
+func TestSearchContextLines(t *testing.T) {
+	dir := t.TempDir()
+	// 行号：1 line1 2 line2 3 MATCH 4 line4 5 line5 6 line6 7 MATCH 8 line8
+	content := "line1\nline2\nMATCH\nline4\nline5\nline6\nMATCH\nline8\n"
+	path := writeTempFile(t, dir, "a.txt", content)
+
+	rep := &recordingReporter{}
+	matchCount, fileCount, _, err := Search(Config{StartDir: dir, Keyword: "MATCH", Regexp: true, Before: 1, After: 1}, rep)
+	if err != nil {
+		t.Fatalf("Search 返回错误: %v", err)
+	}
+	if matchCount != 2 || fileCount != 1 {
+		t.Fatalf("matchCount=%d fileCount=%d，期望 2/1", matchCount, fileCount)
+	}
+	if !rep.closed {
+		t.Error("Reporter.Close 应当在 Search 结束后被调用")
+	}
+
+	matches := rep.byFile[path]
+	if len(matches) != 2 {
+		t.Fatalf("len(matches)=%d，期望 2", len(matches))
+	}
+
+	first := matches[0]
+	if first.Line != 3 || len(first.Before) != 1 || first.Before[0] != "line2" {
+		t.Errorf("第一处匹配 Before 不对: %+v", first)
+	}
+	if len(first.After) != 1 || first.After[0] != "line4" {
+		t.Errorf("第一处匹配 After 不对: %+v", first)
+	}
+
+	second := matches[1]
+	if second.Line != 7 || len(second.Before) != 1 || second.Before[0] != "line6" {
+		t.Errorf("第二处匹配 Before 不对: %+v", second)
+	}
+	if len(second.After) != 1 || second.After[0] != "line8" {
+		t.Errorf("第二处匹配 After 不对: %+v", second)
+	}
+}
+
+func TestSearchContextOverridesByContextFlag(t *testing.T) {
+	dir := t.TempDir()
+	content := "a\nb\nMATCH\nc\nd\n"
+	writeTempFile(t, dir, "a.txt", content)
+
+	rep := &recordingReporter{}
+	// Context 应当覆盖单独设置的 Before/After（与 grep -C 的语义一致）。
+	if _, _, _, err := Search(Config{StartDir: dir, Keyword: "MATCH", Regexp: true, Before: 0, After: 0, Context: 2}, rep); err != nil {
+		t.Fatalf("Search 返回错误: %v", err)
+	}
+	for _, matches := range rep.byFile {
+		if len(matches) != 1 {
+			continue
+		}
+		m := matches[0]
+		if len(m.Before) != 2 || len(m.After) != 2 {
+			t.Errorf("Context=2 应当同时产生 2 行 Before/After，实际 before=%v after=%v", m.Before, m.After)
+		}
+	}
+}
+
+func TestSearchDefaultMaxFileSize(t *testing.T) {
+	// 不显式设置 MaxFileSize 的库调用方（chunk0-3 的典型用法）不应该一个
+	// 匹配都拿不到——回归用例对应 review 中指出的 Search/run 默认值不同步问题。
+	dir := t.TempDir()
+	writeTempFile(t, dir, "a.txt", "MATCH\n")
+
+	rep := &recordingReporter{}
+	matchCount, fileCount, _, err := Search(Config{StartDir: dir, Keyword: "MATCH", Regexp: true}, rep)
+	if err != nil {
+		t.Fatalf("Search 返回错误: %v", err)
+	}
+	if matchCount != 1 || fileCount != 1 {
+		t.Fatalf("matchCount=%d fileCount=%d，期望 1/1（MaxFileSize 未设置时应当退回默认上限，而不是 0）", matchCount, fileCount)
+	}
+}
+
+func TestStreamReporterMergesWindowsAndSeparates(t *testing.T) {
+	var buf bytes.Buffer
+	r := &streamReporter{w: &buf, color: false}
+
+	matches := []Match{
+		{Path: "f.go", Line: 3, MatchStart: 0, MatchEnd: 5, Preview: "MATCH", Before: []string{"line2"}, After: []string{"line4"}},
+		{Path: "f.go", Line: 7, MatchStart: 0, MatchEnd: 5, Preview: "MATCH", Before: []string{"line6"}, After: []string{"line8"}},
+	}
+	r.ReportFile("f.go", matches)
+	r.Close()
+
+	out := buf.String()
+	if !strings.Contains(out, "f.go:") {
+		t.Errorf("输出应当包含文件头，实际: %q", out)
+	}
+	if !strings.Contains(out, "--") {
+		t.Errorf("两个不相邻的匹配窗口之间应当用 \"--\" 分隔，实际: %q", out)
+	}
+	if strings.Count(out, "3: MATCH") != 1 || strings.Count(out, "7: MATCH") != 1 {
+		t.Errorf("两处匹配应当各自只打印一次，实际: %q", out)
+	}
+
+	// 相邻/重叠的窗口应当合并成一块，中间不出现 "--"。
+	buf.Reset()
+	adjacent := []Match{
+		{Path: "g.go", Line: 2, MatchStart: 0, MatchEnd: 5, Preview: "MATCH", Before: []string{"line1"}, After: []string{"line3"}},
+		{Path: "g.go", Line: 4, MatchStart: 0, MatchEnd: 5, Preview: "MATCH", Before: []string{"line3"}, After: []string{"line5"}},
+	}
+	r.ReportFile("g.go", adjacent)
+	if strings.Contains(buf.String(), "--") {
+		t.Errorf("相邻窗口不应被 \"--\" 分隔，实际: %q", buf.String())
+	}
+}
+
+func TestJSONReporterShape(t *testing.T) {
+	var buf bytes.Buffer
+	rep, err := NewReporter("json", &buf)
+	if err != nil {
+		t.Fatalf("NewReporter(json) 失败: %v", err)
+	}
+	want := Match{Path: "f.go", Line: 1, Column: 1, MatchStart: 0, MatchEnd: 5, Preview: "MATCH line"}
+	rep.ReportFile(want.Path, []Match{want})
+	rep.Close()
+
+	var got []Match
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("json 格式输出不是合法的 JSON 数组: %v\n%s", err, buf.String())
+	}
+	if len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+		t.Errorf("json 输出 = %+v，期望 [%+v]", got, want)
+	}
+}
+
+func TestJSONLReporterShape(t *testing.T) {
+	var buf bytes.Buffer
+	rep, err := NewReporter("jsonl", &buf)
+	if err != nil {
+		t.Fatalf("NewReporter(jsonl) 失败: %v", err)
+	}
+	m1 := Match{Path: "a.go", Line: 1, Preview: "one"}
+	m2 := Match{Path: "a.go", Line: 2, Preview: "two"}
+	rep.ReportFile("a.go", []Match{m1, m2})
+	rep.Close()
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("jsonl 输出应当每个 Match 各占一行，实际 %d 行: %q", len(lines), buf.String())
+	}
+	for i, want := range []Match{m1, m2} {
+		var got Match
+		if err := json.Unmarshal([]byte(lines[i]), &got); err != nil {
+			t.Fatalf("第 %d 行不是合法 JSON: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("第 %d 行 = %+v，期望 %+v", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+// Package tui 实现 ffind 的 --interactive 模式：把搜索结果展示成一个
+// 可滚动、可过滤的列表，选中后直接跳转到对应编辑器的对应行。
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fatih/color"
+	"gopkg.in/yaml.v3"
+
+	"www.genji.xin/backend/ffind/internal/searcher"
+)
+
+// Run 启动交互式选择界面，返回用户用 Tab 多选后留下的文件路径列表
+// （用于 pipe 给后续命令）；如果用户只是用 Enter 打开了某一项或者
+// 没有任何多选就退出，返回的 slice 为空。
+func Run(matches []searcher.Match) ([]string, error) {
+	if len(matches) == 0 {
+		fmt.Println("没有可供浏览的匹配结果。")
+		return nil, nil
+	}
+
+	m := newModel(matches)
+	p := tea.NewProgram(m)
+	finalModel, err := p.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	final := finalModel.(model)
+	var picked []string
+	for idx := range final.selected {
+		picked = append(picked, final.matches[idx].Path)
+	}
+	return picked, nil
+}
+
+type model struct {
+	matches  []searcher.Match
+	visible  []int // matches 中通过当前 filter 的下标
+	cursor   int    // visible 中的下标
+	selected map[int]bool
+
+	filtering bool
+	filter    string
+
+	quitting bool
+}
+
+func newModel(matches []searcher.Match) model {
+	m := model{
+		matches:  matches,
+		selected: make(map[int]bool),
+	}
+	m.applyFilter()
+	return m
+}
+
+func (m model) Init() tea.Cmd { return nil }
+
+func (m *model) applyFilter() {
+	m.visible = m.visible[:0]
+	for i, match := range m.matches {
+		if m.filter == "" || matchesFilter(match, m.filter) {
+			m.visible = append(m.visible, i)
+		}
+	}
+	if m.cursor >= len(m.visible) {
+		m.cursor = len(m.visible) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+func matchesFilter(m searcher.Match, filter string) bool {
+	filter = strings.ToLower(filter)
+	return strings.Contains(strings.ToLower(m.Path), filter) ||
+		strings.Contains(strings.ToLower(m.Preview), filter)
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if _, ok := msg.(editorDoneMsg); ok {
+		// 编辑器已经退出，完成一次"跳转到代码"的使命
+		m.quitting = true
+		return m, tea.Quit
+	}
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	if m.filtering {
+		switch keyMsg.Type {
+		case tea.KeyEsc:
+			m.filtering = false
+			m.filter = ""
+			m.applyFilter()
+		case tea.KeyEnter:
+			m.filtering = false
+		case tea.KeyBackspace:
+			if len(m.filter) > 0 {
+				m.filter = m.filter[:len(m.filter)-1]
+				m.applyFilter()
+			}
+		case tea.KeyRunes:
+			m.filter += string(keyMsg.Runes)
+			m.applyFilter()
+		}
+		return m, nil
+	}
+
+	switch keyMsg.String() {
+	case "ctrl+c", "q":
+		m.quitting = true
+		return m, tea.Quit
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.visible)-1 {
+			m.cursor++
+		}
+	case "/":
+		m.filtering = true
+	case "tab":
+		if len(m.visible) > 0 {
+			idx := m.visible[m.cursor]
+			if m.selected[idx] {
+				delete(m.selected, idx)
+			} else {
+				m.selected[idx] = true
+			}
+		}
+	case "enter":
+		if len(m.visible) == 0 {
+			return m, nil
+		}
+		match := m.matches[m.visible[m.cursor]]
+		cmd := editorCommand(match.Path, match.Line)
+		return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+			return editorDoneMsg{err: err}
+		})
+	}
+	return m, nil
+}
+
+// editorDoneMsg 在 $EDITOR/$VISUAL 退出后投递，跳转完成即结束整个 TUI。
+type editorDoneMsg struct{ err error }
+
+func (m model) View() string {
+	if m.quitting {
+		return ""
+	}
+
+	var b strings.Builder
+	header := fmt.Sprintf("ffind 交互模式 — 共 %d 个匹配", len(m.matches))
+	if m.filter != "" || m.filtering {
+		header += fmt.Sprintf("  筛选: %s", m.filter)
+	}
+	b.WriteString(header + "\n")
+	b.WriteString("↑/↓ 或 j/k 移动 · Tab 多选 · Enter 用编辑器打开 · / 筛选 · q 退出\n\n")
+
+	for i, idx := range m.visible {
+		match := m.matches[idx]
+		marker := "  "
+		if m.selected[idx] {
+			marker = "◉ "
+		}
+		line := fmt.Sprintf("%s%s:%d: %s", marker, match.Path, match.Line, match.Preview)
+		if i == m.cursor {
+			b.WriteString(color.New(color.FgBlack, color.BgCyan).Sprint("▸ "+line) + "\n")
+		} else {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+
+	if len(m.visible) > 0 {
+		b.WriteString("\n" + renderPreview(m.matches[m.visible[m.cursor]]))
+	}
+
+	return b.String()
+}
+
+// renderPreview 渲染当前选中匹配的上下文预览，匹配片段高亮显示。
+func renderPreview(match searcher.Match) string {
+	var b strings.Builder
+	b.WriteString("--- 预览 ---\n")
+
+	line := match.Line - len(match.Before)
+	for _, before := range match.Before {
+		b.WriteString(color.New(color.Faint).Sprintf("  %d: %s\n", line, before))
+		line++
+	}
+
+	b.WriteString(fmt.Sprintf("  %d: ", match.Line))
+	b.WriteString(match.Preview[:match.MatchStart])
+	b.WriteString(color.New(color.FgYellow, color.Bold).Sprint(match.Preview[match.MatchStart:match.MatchEnd]))
+	b.WriteString(match.Preview[match.MatchEnd:] + "\n")
+
+	line = match.Line + 1
+	for _, after := range match.After {
+		b.WriteString(color.New(color.Faint).Sprintf("  %d: %s\n", line, after))
+		line++
+	}
+
+	return b.String()
+}
+
+// defaultEditorTemplates 是编辑器名到“打开并跳转到指定行”参数模板的映射，
+// 可以在 ~/.ffind.yaml 里通过 editors 字段覆盖或新增。
+var defaultEditorTemplates = map[string]string{
+	"vim":         "+{line} {path}",
+	"nvim":        "+{line} {path}",
+	"vi":          "+{line} {path}",
+	"code":        "-g {path}:{line}",
+	"emacsclient": "+{line} {path}",
+}
+
+type editorConfig struct {
+	Editors map[string]string `yaml:"editors"`
+}
+
+// loadEditorTemplates 读取 ~/.ffind.yaml 里的 editors 配置，与内置默认值合并。
+func loadEditorTemplates() map[string]string {
+	templates := make(map[string]string, len(defaultEditorTemplates))
+	for k, v := range defaultEditorTemplates {
+		templates[k] = v
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return templates
+	}
+	data, err := os.ReadFile(filepath.Join(home, ".ffind.yaml"))
+	if err != nil {
+		return templates
+	}
+
+	var cfg editorConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return templates
+	}
+	for name, tmpl := range cfg.Editors {
+		templates[name] = tmpl
+	}
+	return templates
+}
+
+// editorCommand 根据 $VISUAL/$EDITOR（默认 vim）构造打开 path 并跳转到
+// line 行的命令。
+func editorCommand(path string, line int) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vim"
+	}
+
+	argTemplate, ok := loadEditorTemplates()[filepath.Base(editor)]
+	if !ok {
+		argTemplate = "+{line} {path}"
+	}
+
+	// 先在模板上按空白切分出各个 argv token，再逐个 token 替换占位符——
+	// path 本身可能含空格，如果先替换再整体 Fields 会把它错误拆成多个参数。
+	replacer := strings.NewReplacer("{line}", strconv.Itoa(line), "{path}", path)
+	tokens := strings.Fields(argTemplate)
+	args := make([]string, len(tokens))
+	for i, t := range tokens {
+		args[i] = replacer.Replace(t)
+	}
+
+	cmd := exec.Command(editor, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd
+}